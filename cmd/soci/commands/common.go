@@ -0,0 +1,63 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package commands holds the shared helpers used across `soci` subcommands.
+package commands
+
+import (
+	"context"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/namespaces"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/urfave/cli/v2"
+)
+
+// NewClient returns a containerd client configured from the global
+// --address/--timeout flags, along with a context already scoped to the
+// requested namespace and a cancel func the caller must defer.
+func NewClient(cliContext *cli.Context) (context.Context, *containerd.Client, context.CancelFunc, error) {
+	client, err := containerd.New(cliContext.String("address"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(cliContext.Context, cliContext.Duration("timeout"))
+	ctx = namespaces.WithNamespace(ctx, Namespace(cliContext))
+	return ctx, client, cancel, nil
+}
+
+// Namespace returns the containerd namespace requested on the command line,
+// defaulting to "default".
+func Namespace(cliContext *cli.Context) string {
+	if ns := cliContext.String("namespace"); ns != "" {
+		return ns
+	}
+	return "default"
+}
+
+// ChildManifests returns the platform-specific manifest descriptors for img,
+// resolving through any manifest list/index.
+func ChildManifests(ctx context.Context, client *containerd.Client, img containerd.Image) ([]ocispec.Descriptor, error) {
+	target := img.Target()
+	switch target.MediaType {
+	case ocispec.MediaTypeImageIndex, images.MediaTypeDockerSchema2ManifestList:
+		return images.Children(ctx, client.ContentStore(), target)
+	default:
+		// img already resolves to a single manifest.
+		return []ocispec.Descriptor{target}, nil
+	}
+}
@@ -0,0 +1,103 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/awslabs/soci-snapshotter/soci"
+	"github.com/containerd/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/urfave/cli/v2"
+)
+
+var platformFlag = &cli.StringSliceFlag{
+	Name:    "platform",
+	Aliases: []string{"p"},
+	Usage:   "build a SOCI index for the given platform(s) instead of the host platform; repeatable and/or comma-separated (e.g. --platform linux/amd64,linux/arm64)",
+}
+
+// CreateCommand builds a SOCI index for an image already present in
+// containerd's content store.
+var CreateCommand = &cli.Command{
+	Name:      "create",
+	Usage:     "create a SOCI index for an image",
+	ArgsUsage: "<image_ref>",
+	Flags: []cli.Flag{
+		platformFlag,
+	},
+	Action: func(cliContext *cli.Context) error {
+		imageRef := cliContext.Args().First()
+		if imageRef == "" {
+			return fmt.Errorf("image ref must be provided")
+		}
+
+		ctx, client, cancel, err := NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		wanted, err := soci.ParsePlatforms(cliContext.StringSlice(platformFlag.Name))
+		if err != nil {
+			return err
+		}
+
+		img, err := client.GetImage(ctx, imageRef)
+		if err != nil {
+			return fmt.Errorf("unable to resolve image %s: %w", imageRef, err)
+		}
+
+		manifests, err := ChildManifests(ctx, client, img)
+		if err != nil {
+			return err
+		}
+
+		var targets []ocispec.Descriptor
+		if len(wanted) == 0 {
+			// No --platform given: preserve the existing single-platform
+			// behavior and build for the host platform only. A single,
+			// already-resolved manifest (the common case, no manifest list)
+			// has no Platform descriptor to match against, so only run it
+			// through the default-platform matcher when img actually
+			// resolved to more than one candidate manifest.
+			if len(manifests) <= 1 {
+				targets = manifests
+			} else {
+				targets, err = soci.MatchManifests(manifests, []ocispec.Platform{platforms.DefaultSpec()})
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			targets, err = soci.MatchManifests(manifests, wanted)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, target := range targets {
+			digest, err := soci.BuildIndex(ctx, client, img, target)
+			if err != nil {
+				return fmt.Errorf("unable to build SOCI index for %s (%s): %w", imageRef, target.Platform, err)
+			}
+			fmt.Fprintln(cliContext.App.Writer, digest.String())
+		}
+
+		return nil
+	},
+}
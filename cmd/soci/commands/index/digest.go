@@ -0,0 +1,148 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/awslabs/soci-snapshotter/cmd/soci/commands"
+	"github.com/awslabs/soci-snapshotter/soci"
+	"github.com/containerd/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/urfave/cli/v2"
+)
+
+var digestCommand = &cli.Command{
+	Name:      "digest",
+	Usage:     "print the digest to reference for an image, collapsing single-manifest SOCI indices",
+	ArgsUsage: "<image_ref>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "platform",
+			Usage: "disambiguate which platform's SOCI index to inspect when the image has more than one",
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q"},
+			Value:   true,
+			Usage:   "print only the digest (default)",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: `set to "json" to emit {"ref":..., "digest":..., "collapsed":bool, "children":[...]}`,
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		imageRef := cliContext.Args().First()
+		if imageRef == "" {
+			return fmt.Errorf("image ref must be provided")
+		}
+
+		ctx, client, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		all, err := soci.ListStoredIndices(ctx, client.ContentStore())
+		if err != nil {
+			return fmt.Errorf("unable to list SOCI indices: %w", err)
+		}
+
+		matches := selectIndicesForRef(all, imageRef)
+		if platform := cliContext.String("platform"); platform != "" {
+			p, err := platforms.Parse(platform)
+			if err != nil {
+				return fmt.Errorf("invalid --platform %s: %w", platform, err)
+			}
+			matches = filterByPlatform(matches, p)
+		}
+
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("no SOCI index found for %s; use --platform to disambiguate if the image has multiple", imageRef)
+		case 1:
+			// fall through
+		default:
+			return fmt.Errorf("image %s resolves to %d SOCI indices; use --platform to disambiguate", imageRef, len(matches))
+		}
+		idx := matches[0]
+
+		result, err := soci.CollapseIndex(ctx, client, idx)
+		if err != nil {
+			return err
+		}
+
+		if cliContext.String("format") == "json" {
+			out, err := json.Marshal(digestResult{
+				Ref:       imageRef,
+				Digest:    result.Digest.String(),
+				Collapsed: result.Collapsed,
+				Children:  result.Children,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cliContext.App.Writer, string(out))
+			return nil
+		}
+
+		if cliContext.Bool("quiet") {
+			fmt.Fprintln(cliContext.App.Writer, result.Digest.String())
+			return nil
+		}
+
+		collapsedFrom := "index"
+		if result.Collapsed {
+			collapsedFrom = "child manifest"
+		}
+		fmt.Fprintf(cliContext.App.Writer, "%s: %s (%s, %d children)\n", imageRef, result.Digest.String(), collapsedFrom, len(result.Children))
+		return nil
+	},
+}
+
+type digestResult struct {
+	Ref       string   `json:"ref"`
+	Digest    string   `json:"digest"`
+	Collapsed bool     `json:"collapsed"`
+	Children  []string `json:"children"`
+}
+
+func selectIndicesForRef(all []soci.StoredIndex, ref string) []soci.StoredIndex {
+	var out []soci.StoredIndex
+	for _, idx := range all {
+		if idx.ImageRef == ref {
+			out = append(out, idx)
+		}
+	}
+	return out
+}
+
+func filterByPlatform(indices []soci.StoredIndex, wanted ocispec.Platform) []soci.StoredIndex {
+	var out []soci.StoredIndex
+	for _, idx := range indices {
+		have, err := platforms.Parse(idx.Platform)
+		if err != nil {
+			continue
+		}
+		if platforms.NewMatcher(wanted).Match(have) {
+			out = append(out, idx)
+		}
+	}
+	return out
+}
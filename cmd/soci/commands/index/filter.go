@@ -0,0 +1,141 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/awslabs/soci-snapshotter/soci"
+	"github.com/containerd/containerd/platforms"
+)
+
+// filterGroup holds every `--filter key=value` occurrence for a single key.
+// Values within a group are ORed together; distinct groups are ANDed, same
+// as `docker image ls --filter`.
+type filterGroup struct {
+	key    string
+	values []string
+}
+
+// parseFilterFlags groups repeated `--filter key=value` flags by key,
+// preserving first-seen key order.
+func parseFilterFlags(raw []string) ([]filterGroup, error) {
+	order := make([]string, 0, len(raw))
+	byKey := make(map[string][]string)
+	for _, f := range raw {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --filter %q: expected key=value", f)
+		}
+		switch key {
+		case "ref", "platform", "media-type", "before", "since", "label":
+		default:
+			return nil, fmt.Errorf("invalid --filter key %q", key)
+		}
+		if _, ok := byKey[key]; !ok {
+			order = append(order, key)
+		}
+		byKey[key] = append(byKey[key], value)
+	}
+
+	groups := make([]filterGroup, 0, len(order))
+	for _, key := range order {
+		groups = append(groups, filterGroup{key: key, values: byKey[key]})
+	}
+	return groups, nil
+}
+
+// applyFilters keeps only the indices matching every filter group (AND
+// across groups, OR within a group's repeated values). all is the full,
+// unfiltered set of indices, needed to resolve before/since references.
+func applyFilters(all []soci.StoredIndex, groups []filterGroup) ([]soci.StoredIndex, error) {
+	out := all
+	for _, g := range groups {
+		var kept []soci.StoredIndex
+		for _, idx := range out {
+			matched := false
+			for _, value := range g.values {
+				ok, err := matchesFilter(g.key, value, idx, all)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				kept = append(kept, idx)
+			}
+		}
+		out = kept
+	}
+	return out, nil
+}
+
+func matchesFilter(key, value string, idx soci.StoredIndex, all []soci.StoredIndex) (bool, error) {
+	switch key {
+	case "ref":
+		return filepath.Match(value, idx.ImageRef)
+	case "media-type":
+		return idx.MediaType == value, nil
+	case "platform":
+		wanted, err := platforms.Parse(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid --filter platform=%s: %w", value, err)
+		}
+		have, err := platforms.Parse(idx.Platform)
+		if err != nil {
+			return false, nil
+		}
+		return platforms.NewMatcher(wanted).Match(have), nil
+	case "label":
+		k, v, hasValue := strings.Cut(value, "=")
+		got, ok := idx.Labels[k]
+		if !ok {
+			return false, nil
+		}
+		if !hasValue {
+			return true, nil
+		}
+		return got == v, nil
+	case "before", "since":
+		ref, err := resolveFilterReference(value, all)
+		if err != nil {
+			return false, err
+		}
+		if key == "before" {
+			return idx.CreatedAt.Before(ref.CreatedAt), nil
+		}
+		return idx.CreatedAt.After(ref.CreatedAt), nil
+	default:
+		return false, fmt.Errorf("invalid --filter key %q", key)
+	}
+}
+
+// resolveFilterReference finds the index a before=/since= value points at,
+// by digest or by image ref.
+func resolveFilterReference(value string, all []soci.StoredIndex) (soci.StoredIndex, error) {
+	for _, idx := range all {
+		if idx.Digest.String() == value || idx.ImageRef == value {
+			return idx, nil
+		}
+	}
+	return soci.StoredIndex{}, fmt.Errorf("--filter before/since: no SOCI index found matching %q", value)
+}
@@ -0,0 +1,88 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/awslabs/soci-snapshotter/soci"
+)
+
+// formatRow is what --format json / --format <template> renders each index
+// as; field names match the struct tags so `--format json` and `{{.Digest}}`
+// style templates see the same vocabulary.
+type formatRow struct {
+	Digest    string    `json:"digest"`
+	ImageRef  string    `json:"imageRef"`
+	Platform  string    `json:"platform"`
+	Size      int64     `json:"size"`
+	MediaType string    `json:"mediaType"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func toFormatRow(idx soci.StoredIndex) formatRow {
+	return formatRow{
+		Digest:    idx.Digest.String(),
+		ImageRef:  idx.ImageRef,
+		Platform:  idx.Platform,
+		Size:      idx.Size,
+		MediaType: idx.MediaType,
+		CreatedAt: idx.CreatedAt,
+	}
+}
+
+// writeFormatted renders indices to w according to format, which is one of
+// "json" (newline-delimited JSON objects), "json-array" (a single JSON
+// array), or a Go text/template referencing .Digest, .ImageRef, .Platform,
+// .Size, .MediaType, .CreatedAt.
+func writeFormatted(w io.Writer, indices []soci.StoredIndex, format string) error {
+	rows := make([]formatRow, len(indices))
+	for i, idx := range indices {
+		rows[i] = toFormatRow(idx)
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json-array":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	default:
+		tmpl, err := template.New("format").Parse(format)
+		if err != nil {
+			return fmt.Errorf("invalid --format template: %w", err)
+		}
+		for _, row := range rows {
+			if err := tmpl.Execute(w, row); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/awslabs/soci-snapshotter/cmd/soci/commands"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/urfave/cli/v2"
+)
+
+var infoCommand = &cli.Command{
+	Name:      "info",
+	Usage:     "print the contents of a SOCI index",
+	ArgsUsage: "<digest>",
+	Action: func(cliContext *cli.Context) error {
+		dgst, err := digest.Parse(cliContext.Args().First())
+		if err != nil {
+			return fmt.Errorf("invalid SOCI index digest: %w", err)
+		}
+
+		ctx, client, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		ra, err := client.ContentStore().ReaderAt(ctx, ocispec.Descriptor{Digest: dgst})
+		if err != nil {
+			return fmt.Errorf("unable to read SOCI index %s: %w", dgst, err)
+		}
+		defer ra.Close()
+
+		raw := make([]byte, ra.Size())
+		if _, err := ra.ReadAt(raw, 0); err != nil {
+			return fmt.Errorf("unable to read SOCI index %s: %w", dgst, err)
+		}
+
+		var pretty map[string]any
+		if err := json.Unmarshal(raw, &pretty); err != nil {
+			return fmt.Errorf("invalid SOCI index %s: %w", dgst, err)
+		}
+		out, err := json.MarshalIndent(pretty, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cliContext.App.Writer, string(out))
+		return nil
+	},
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/awslabs/soci-snapshotter/cmd/soci/commands"
+	"github.com/awslabs/soci-snapshotter/soci"
+	"github.com/urfave/cli/v2"
+)
+
+var listCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Usage:   "list SOCI indices stored locally",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Aliases: []string{"q"},
+			Usage:   "only print SOCI index digests",
+		},
+		&cli.StringFlag{
+			Name:  "ref",
+			Usage: "only list SOCI indices for the given image ref; shorthand for --filter ref=<ref>",
+		},
+		&cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "only list SOCI indices matching the given platform(s); repeatable and/or comma-separated, ORed together; shorthand for --filter platform=<platform>",
+		},
+		&cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "filter indices by key=value (ref=<glob>, platform=<os/arch>, media-type=<mt>, before=<digest|ref>, since=<digest|ref>, label=<k>[=<v>]); repeatable, ANDed across keys and ORed within a repeated key",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: `output format: "json" (one object per line), "json-array", or a Go text/template referencing .Digest, .ImageRef, .Platform, .Size, .MediaType, .CreatedAt`,
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ctx, client, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		indices, err := soci.ListStoredIndices(ctx, client.ContentStore())
+		if err != nil {
+			return fmt.Errorf("unable to list SOCI indices: %w", err)
+		}
+
+		groups, err := parseFilterFlags(cliContext.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+		if ref := cliContext.String("ref"); ref != "" {
+			groups = append(groups, filterGroup{key: "ref", values: []string{ref}})
+		}
+		if platformValues := cliContext.StringSlice("platform"); len(platformValues) > 0 {
+			var values []string
+			for _, group := range platformValues {
+				for _, p := range strings.Split(group, ",") {
+					if p = strings.TrimSpace(p); p != "" {
+						values = append(values, p)
+					}
+				}
+			}
+			groups = append(groups, filterGroup{key: "platform", values: values})
+		}
+
+		indices, err = applyFilters(indices, groups)
+		if err != nil {
+			return err
+		}
+		sort.Slice(indices, func(i, j int) bool { return indices[i].Digest.String() < indices[j].Digest.String() })
+
+		if format := cliContext.String("format"); format != "" {
+			return writeFormatted(cliContext.App.Writer, indices, format)
+		}
+
+		if cliContext.Bool("quiet") {
+			for _, idx := range indices {
+				fmt.Fprintln(cliContext.App.Writer, idx.Digest.String())
+			}
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(cliContext.App.Writer, 1, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "DIGEST\tIMAGE REF\tPLATFORM")
+		for _, idx := range indices {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", idx.Digest.String(), idx.ImageRef, idx.Platform)
+		}
+		return tw.Flush()
+	},
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package index
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/awslabs/soci-snapshotter/cmd/soci/commands"
+	"github.com/awslabs/soci-snapshotter/soci"
+	"github.com/urfave/cli/v2"
+)
+
+var pruneCommand = &cli.Command{
+	Name:  "prune",
+	Usage: "remove SOCI indices whose image or blobs no longer exist",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print what would be deleted without deleting anything; exits non-zero if anything would be pruned",
+		},
+		&cli.DurationFlag{
+			Name:  "until",
+			Usage: "only prune indices older than this duration (e.g. 24h)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "scope the sweep with key=value filters, same syntax as `soci index list --filter`",
+		},
+		&cli.IntFlag{
+			Name:  "keep-last",
+			Usage: "always retain the N most recent indices per image ref",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ctx, client, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		store := client.ContentStore()
+		all, err := soci.ListStoredIndices(ctx, store)
+		if err != nil {
+			return fmt.Errorf("unable to list SOCI indices: %w", err)
+		}
+
+		groups, err := parseFilterFlags(cliContext.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+		scope, err := applyFilters(all, groups)
+		if err != nil {
+			return err
+		}
+
+		candidates, err := soci.FindOrphanedIndices(ctx, client, store, scope, cliContext.Int("keep-last"))
+		if err != nil {
+			return err
+		}
+
+		if until := cliContext.Duration("until"); until > 0 {
+			cutoff := time.Now().Add(-until)
+			candidates = filterByAge(candidates, cutoff)
+		}
+
+		for _, c := range candidates {
+			fmt.Fprintf(cliContext.App.Writer, "%s\t%s\t%s\n", c.Digest, c.ImageRef, c.Reason)
+		}
+
+		if cliContext.Bool("dry-run") {
+			if len(candidates) > 0 {
+				return fmt.Errorf("%d SOCI index(es) would be pruned", len(candidates))
+			}
+			return nil
+		}
+
+		reclaimed, err := soci.PruneStoredIndices(ctx, store, candidates)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cliContext.App.Writer, "reclaimed %d bytes\n", reclaimed)
+		return nil
+	},
+}
+
+func filterByAge(candidates []soci.PruneCandidate, cutoff time.Time) []soci.PruneCandidate {
+	var out []soci.PruneCandidate
+	for _, c := range candidates {
+		if c.CreatedAt.Before(cutoff) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
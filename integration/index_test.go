@@ -18,14 +18,43 @@ package integration
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/awslabs/soci-snapshotter/soci"
 	"github.com/awslabs/soci-snapshotter/util/dockershell"
 	"github.com/containerd/containerd/platforms"
 )
 
+// runExpectingExit runs args through a shell and returns its stdout together
+// with its real exit code. Use this instead of sh.O for invocations the
+// feature being tested is specced to fail on: sh.O is used everywhere else
+// in this package for commands expected to succeed, and its handling of a
+// nonzero exit is not something a failing case should rely on.
+func runExpectingExit(t *testing.T, sh *dockershell.Shell, args ...string) (string, int) {
+	t.Helper()
+	const marker = "SOCI_TEST_EXIT_CODE"
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	cmd := strings.Join(quoted, " ") + fmt.Sprintf("; echo %s:$?", marker)
+	raw := string(sh.O("sh", "-c", cmd))
+
+	idx := strings.LastIndex(raw, marker+":")
+	if idx == -1 {
+		t.Fatalf("expected exit marker in output of %q, got:\n%s", args, raw)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(raw[idx+len(marker)+1:]))
+	if err != nil {
+		t.Fatalf("invalid exit code in output of %q: %v", args, err)
+	}
+	return raw[:idx], code
+}
+
 type testImageIndex struct {
 	imgName         string
 	platform        string
@@ -153,6 +182,12 @@ func TestSociIndexList(t *testing.T) {
 			filter:       func(img testImageIndex) bool { return img.platform == "linux/arm64" },
 			existHandler: existHandlerFull,
 		},
+		{
+			name:         "`soci index ls --platform linux/arm64,linux/amd64` should OR the platform filters together",
+			command:      []string{"soci", "index", "list", "--platform", "linux/arm64,linux/amd64"},
+			filter:       func(img testImageIndex) bool { return img.platform == "linux/arm64" || img.platform == "linux/amd64" },
+			existHandler: existHandlerFull,
+		},
 		{
 			// make sure the image only generates one soci index (the test expects a single digest output)
 			name:         "`soci index ls --ref imgRef -q` should print the exact soci index digest",
@@ -177,3 +212,289 @@ func TestSociIndexList(t *testing.T) {
 		})
 	}
 }
+
+// TestSociIndexListManyIndices seeds a few dozen SOCI indices (all built
+// from the same already-pulled image, retagged under distinct refs, to
+// avoid dozens of registry pulls) and asserts that `soci index list` stays
+// fast and returns a stably sorted result as the fan-out in ListStoredIndices
+// grows.
+func TestSociIndexListManyIndices(t *testing.T) {
+	t.Parallel()
+	sh, done := newSnapshotterBaseShell(t)
+	defer done()
+	rebootContainerd(t, sh, "", "")
+
+	const numIndices = 50
+
+	base := dockerhub("alpine:latest", withPlatform(platforms.DefaultSpec()))
+	optimizeImage(sh, base)
+
+	for i := 0; i < numIndices; i++ {
+		ref := fmt.Sprintf("%s-seed-%d", base.ref, i)
+		sh.O("ctr", "-n", "k8s.io", "images", "tag", base.ref, ref)
+		sh.O("soci", "create", ref)
+	}
+
+	start := time.Now()
+	first := string(sh.O("soci", "index", "list", "-q"))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("`soci index list` took %v listing %d indices; expected the errgroup fan-out to keep this well under 5s", elapsed, numIndices)
+	}
+
+	lines := strings.Split(strings.TrimRight(first, "\n"), "\n")
+	if len(lines) < numIndices {
+		t.Fatalf("expected at least %d indices, got %d: %q", numIndices, len(lines), first)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i-1] > lines[i] {
+			t.Fatalf("`soci index list` output is not sorted by digest: %q appears before %q", lines[i-1], lines[i])
+		}
+	}
+
+	second := string(sh.O("soci", "index", "list", "-q"))
+	if first != second {
+		t.Fatalf("`soci index list` output is not stable across repeated invocations:\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+}
+
+// indexListRow mirrors the JSON shape `soci index list --format json`
+// emits, so tests can unmarshal the output instead of matching substrings.
+type indexListRow struct {
+	Digest    string    `json:"digest"`
+	ImageRef  string    `json:"imageRef"`
+	Platform  string    `json:"platform"`
+	Size      int64     `json:"size"`
+	MediaType string    `json:"mediaType"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func TestSociIndexListFilterAndFormat(t *testing.T) {
+	t.Parallel()
+	sh, done := newSnapshotterBaseShell(t)
+	defer done()
+	rebootContainerd(t, sh, "", "")
+
+	testImages := prepareSociIndices(t, sh)
+
+	decodeNDJSON := func(output []byte) []indexListRow {
+		var rows []indexListRow
+		for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			var row indexListRow
+			if err := json.Unmarshal([]byte(line), &row); err != nil {
+				t.Fatalf("invalid json line from `soci index list --format json`: %q: %v", line, err)
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+		filter  func(img testImageIndex) bool
+	}{
+		{
+			name:    "--filter ref=<glob> should match the given image ref",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "ref=" + testImages[0].imgInfo.ref},
+			filter:  func(img testImageIndex) bool { return img.imgInfo.ref == testImages[0].imgInfo.ref },
+		},
+		{
+			name:    "--filter platform=<os/arch> should match the given platform",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "platform=linux/arm64"},
+			filter:  func(img testImageIndex) bool { return img.platform == "linux/arm64" },
+		},
+		{
+			name:    "--filter ref=<glob>,platform=<os/arch> should AND across keys",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "ref=" + testImages[0].imgInfo.ref, "--filter", "platform=linux/arm64"},
+			filter: func(img testImageIndex) bool {
+				return img.imgInfo.ref == testImages[0].imgInfo.ref && img.platform == "linux/arm64"
+			},
+		},
+		{
+			name:    "--filter media-type=<mt> should match the SOCI index media type",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "media-type=application/vnd.amazon.soci.index.v1+json"},
+			filter:  func(img testImageIndex) bool { return true },
+		},
+		{
+			name:    "--filter media-type=<unknown mt> should match nothing",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "media-type=application/does.not.exist"},
+			filter:  func(img testImageIndex) bool { return false },
+		},
+		{
+			name:    "--filter label=<key> should match indices carrying that label, regardless of value",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "label=containerd.io/snapshot/soci/index"},
+			filter:  func(img testImageIndex) bool { return true },
+		},
+		{
+			name:    "--filter label=<key>=<value> should match only indices with that exact label value",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "label=containerd.io/snapshot/soci/index=true"},
+			filter:  func(img testImageIndex) bool { return true },
+		},
+		{
+			name:    "--filter label=<key>=<wrong value> should match nothing",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "label=containerd.io/snapshot/soci/index=false"},
+			filter:  func(img testImageIndex) bool { return false },
+		},
+		{
+			name:    "--filter before=<digest> should match only indices built before the reference one",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "before=" + testImages[len(testImages)-1].sociIndexDigest},
+			filter: func(img testImageIndex) bool {
+				return img.sociIndexDigest != testImages[len(testImages)-1].sociIndexDigest
+			},
+		},
+		{
+			name:    "--filter since=<digest> should match only indices built after the reference one",
+			command: []string{"soci", "index", "list", "--format", "json", "--filter", "since=" + testImages[0].sociIndexDigest},
+			filter: func(img testImageIndex) bool {
+				return img.sociIndexDigest != testImages[0].sociIndexDigest
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := decodeNDJSON(sh.O(tt.command...))
+			for _, img := range testImages {
+				expected := tt.filter(img)
+				var found bool
+				for _, row := range rows {
+					if row.Digest == img.sociIndexDigest {
+						found = true
+						break
+					}
+				}
+				if expected != found {
+					t.Fatalf("image %s: expected present=%v, got present=%v", img.imgInfo.ref, expected, found)
+				}
+			}
+		})
+	}
+
+	t.Run("--format json-array should decode as a single JSON array", func(t *testing.T) {
+		var rows []indexListRow
+		rawJSON := sh.O("soci", "index", "list", "--format", "json-array")
+		if err := json.Unmarshal(rawJSON, &rows); err != nil {
+			t.Fatalf("invalid json-array from `soci index list --format json-array`: %v", rawJSON)
+		}
+		if len(rows) < len(testImages) {
+			t.Fatalf("expected at least %d rows, got %d", len(testImages), len(rows))
+		}
+	})
+
+	t.Run("--format template should render requested fields", func(t *testing.T) {
+		output := string(sh.O("soci", "index", "list", "--format", "{{.Digest}} {{.ImageRef}}"))
+		for _, img := range testImages {
+			want := img.sociIndexDigest + " " + img.imgInfo.ref
+			if !strings.Contains(output, want) {
+				t.Fatalf("expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+	})
+}
+
+// TestSociIndexPrune builds indices for several images, removes the
+// underlying images for some of them, and verifies `soci index prune`
+// removes exactly the now-orphaned indices and leaves the rest untouched.
+func TestSociIndexPrune(t *testing.T) {
+	t.Parallel()
+	sh, done := newSnapshotterBaseShell(t)
+	defer done()
+	rebootContainerd(t, sh, "", "")
+
+	testImages := prepareSociIndices(t, sh)
+
+	orphaned := testImages[:2]
+	live := testImages[2:]
+	for _, img := range orphaned {
+		sh.O("ctr", "-n", "k8s.io", "images", "rm", img.imgInfo.ref)
+	}
+
+	dryRunOutput, dryRunExit := runExpectingExit(t, sh, "soci", "index", "prune", "--dry-run")
+	if dryRunExit == 0 {
+		t.Fatalf("expected `soci index prune --dry-run` to exit nonzero when orphaned indices are found, output:\n%s", dryRunOutput)
+	}
+	for _, img := range orphaned {
+		if !strings.Contains(string(dryRunOutput), img.sociIndexDigest) {
+			t.Fatalf("expected --dry-run output to list orphaned index %s:\n%s", img.sociIndexDigest, dryRunOutput)
+		}
+	}
+	for _, img := range live {
+		if strings.Contains(string(dryRunOutput), img.sociIndexDigest) {
+			t.Fatalf("did not expect --dry-run output to list live index %s:\n%s", img.sociIndexDigest, dryRunOutput)
+		}
+	}
+
+	sh.O("soci", "index", "prune")
+
+	remaining := string(sh.O("soci", "index", "list", "-q"))
+	for _, img := range orphaned {
+		if strings.Contains(remaining, img.sociIndexDigest) {
+			t.Fatalf("expected orphaned index %s to be pruned, still present in:\n%s", img.sociIndexDigest, remaining)
+		}
+	}
+	for _, img := range live {
+		if !strings.Contains(remaining, img.sociIndexDigest) {
+			t.Fatalf("expected live index %s to survive prune, missing from:\n%s", img.sociIndexDigest, remaining)
+		}
+	}
+}
+
+// digestCommandResult mirrors the JSON shape `soci index digest --format
+// json` emits.
+type digestCommandResult struct {
+	Ref       string   `json:"ref"`
+	Digest    string   `json:"digest"`
+	Collapsed bool     `json:"collapsed"`
+	Children  []string `json:"children"`
+}
+
+// TestSociIndexDigest verifies the collapsing behavior of `soci index
+// digest`: a ref with exactly one matching SOCI index collapses to that
+// index's single child manifest digest, while a ref with more than one
+// matching index (multiple platforms built for the same ref) is rejected
+// as ambiguous until disambiguated with --platform.
+func TestSociIndexDigest(t *testing.T) {
+	t.Parallel()
+	sh, done := newSnapshotterBaseShell(t)
+	defer done()
+	rebootContainerd(t, sh, "", "")
+
+	testImages := prepareSociIndices(t, sh)
+
+	t.Run("single matching index collapses to its child manifest digest", func(t *testing.T) {
+		img := testImages[0]
+		var result digestCommandResult
+		rawJSON := sh.O("soci", "index", "digest", img.imgInfo.ref, "--format", "json")
+		if err := json.Unmarshal(rawJSON, &result); err != nil {
+			t.Fatalf("invalid `soci index digest` json output: %s: %v", rawJSON, err)
+		}
+		if !result.Collapsed {
+			t.Fatalf("expected a single-platform image to collapse, got: %+v", result)
+		}
+		if len(result.Children) != 1 {
+			t.Fatalf("expected exactly one child manifest, got: %+v", result)
+		}
+		if result.Digest == img.sociIndexDigest {
+			t.Fatalf("expected the collapsed digest to differ from the SOCI index digest itself, got %s for both", result.Digest)
+		}
+	})
+
+	t.Run("multiple indices for the same ref require --platform", func(t *testing.T) {
+		img := testImages[0]
+		otherPlatform := "linux/arm/v7"
+		sh.O("soci", "create", "--platform", otherPlatform, img.imgInfo.ref)
+
+		ambiguous, ambiguousExit := runExpectingExit(t, sh, "soci", "index", "digest", img.imgInfo.ref)
+		if ambiguousExit == 0 {
+			t.Fatalf("expected `soci index digest %s` to exit nonzero without --platform when multiple indices match, got output: %s", img.imgInfo.ref, ambiguous)
+		}
+
+		resolved := string(sh.O("soci", "index", "digest", img.imgInfo.ref, "--platform", img.platform))
+		if strings.TrimSpace(resolved) == "" {
+			t.Fatalf("expected --platform to disambiguate and print a digest")
+		}
+	})
+}
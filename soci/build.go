@@ -0,0 +1,34 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package soci
+
+import (
+	"context"
+
+	"github.com/containerd/containerd"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// BuildIndex builds a SOCI index for a single platform instance of img and
+// returns the digest of the resulting index manifest. Callers that need
+// indices for several platforms call BuildIndex once per matched instance
+// (see MatchManifests).
+func BuildIndex(ctx context.Context, client *containerd.Client, img containerd.Image, target ocispec.Descriptor) (digest.Digest, error) {
+	builder := newIndexBuilder(client, img, target)
+	return builder.build(ctx)
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package soci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// CollapsedDigest is the result of collapsing a SOCI index down to the
+// single digest a downstream tool (signing, attestation, deployment
+// manifests) should reference.
+type CollapsedDigest struct {
+	// Digest is the child manifest's digest when the index collapses to
+	// exactly one, otherwise the SOCI index's own digest.
+	Digest digest.Digest
+	// Collapsed is true when Digest names a child manifest rather than the
+	// index itself.
+	Collapsed bool
+	// Children lists the digests of every child manifest in the index, for
+	// callers that want the full picture even when not collapsed.
+	Children []string
+}
+
+// CollapseIndex returns the digest a caller should reference for idx: if
+// its SOCI index manifest has exactly one child manifest, that child's
+// digest; otherwise the index's own digest.
+func CollapseIndex(ctx context.Context, client *containerd.Client, idx StoredIndex) (CollapsedDigest, error) {
+	children, err := readIndexChildren(ctx, client.ContentStore(), idx.Digest)
+	if err != nil {
+		return CollapsedDigest{}, fmt.Errorf("unable to read SOCI index %s: %w", idx.Digest, err)
+	}
+
+	childDigests := make([]string, len(children))
+	for i, c := range children {
+		childDigests[i] = c.String()
+	}
+
+	if len(children) == 1 {
+		return CollapsedDigest{Digest: children[0], Collapsed: true, Children: childDigests}, nil
+	}
+	return CollapsedDigest{Digest: idx.Digest, Collapsed: false, Children: childDigests}, nil
+}
+
+// readIndexChildren reads the raw SOCI index manifest and returns the
+// digests of its child manifests.
+func readIndexChildren(ctx context.Context, store content.Store, dgst digest.Digest) ([]digest.Digest, error) {
+	ra, err := store.ReaderAt(ctx, ocispec.Descriptor{Digest: dgst})
+	if err != nil {
+		return nil, err
+	}
+	defer ra.Close()
+
+	raw, err := io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Manifests []ocispec.Descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, err
+	}
+
+	digests := make([]digest.Digest, len(manifest.Manifests))
+	for i, m := range manifest.Manifests {
+		digests[i] = m.Digest
+	}
+	return digests, nil
+}
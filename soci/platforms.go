@@ -0,0 +1,89 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package soci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/containerd/containerd/platforms"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ParsePlatforms parses a repeatable, comma-separated --platform flag value
+// (e.g. []string{"linux/amd64,linux/arm64"}) into a deduplicated list of
+// platforms, in first-seen order.
+func ParsePlatforms(raw []string) ([]ocispec.Platform, error) {
+	var out []ocispec.Platform
+	seen := make(map[string]struct{})
+	for _, group := range raw {
+		for _, entry := range strings.Split(group, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			p, err := platforms.Parse(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid platform %q: %w", entry, err)
+			}
+			key := platforms.Format(p)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// MatchManifests resolves each requested platform against the manifests in
+// an image's manifest list, returning the matching descriptors deduplicated
+// by digest (two requested platforms that resolve to the same manifest only
+// appear once), in the order the requested platforms were given. If any
+// requested platform has no matching manifest, MatchManifests returns an
+// error naming all such platforms rather than failing on the first miss.
+func MatchManifests(manifests []ocispec.Descriptor, wanted []ocispec.Platform) ([]ocispec.Descriptor, error) {
+	var matched []ocispec.Descriptor
+	var unmatched []string
+	seen := make(map[digest.Digest]struct{})
+	for _, p := range wanted {
+		m := platforms.NewMatcher(p)
+		found := false
+		for _, desc := range manifests {
+			if desc.Platform == nil {
+				continue
+			}
+			if m.Match(*desc.Platform) {
+				found = true
+				if _, ok := seen[desc.Digest]; !ok {
+					seen[desc.Digest] = struct{}{}
+					matched = append(matched, desc)
+				}
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, platforms.Format(p))
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("no manifest found for platform(s): %s", strings.Join(unmatched, ", "))
+	}
+	return matched, nil
+}
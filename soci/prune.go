@@ -0,0 +1,160 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package soci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+)
+
+// PruneCandidate is a stored index that FindOrphanedIndices has decided is an
+// orphan: its image manifest or one of its referenced blobs is gone.
+type PruneCandidate struct {
+	StoredIndex
+	Reason string
+}
+
+// FindOrphanedIndices returns the indices in all whose backing image
+// manifest is no longer present in the containerd image store, or whose
+// blobs are missing from store, skipping up to keepLast most-recent indices
+// per image ref (0 disables this).
+func FindOrphanedIndices(ctx context.Context, client *containerd.Client, store content.Store, all []StoredIndex, keepLast int) ([]PruneCandidate, error) {
+	kept := make(map[digestKey]struct{})
+	if keepLast > 0 {
+		byRef := make(map[string][]StoredIndex)
+		for _, idx := range all {
+			byRef[idx.ImageRef] = append(byRef[idx.ImageRef], idx)
+		}
+		for _, group := range byRef {
+			sort.Slice(group, func(i, j int) bool { return group[i].CreatedAt.After(group[j].CreatedAt) })
+			for i := 0; i < len(group) && i < keepLast; i++ {
+				kept[digestKey{group[i].ImageRef, group[i].Digest.String()}] = struct{}{}
+			}
+		}
+	}
+
+	var candidates []PruneCandidate
+	for _, idx := range all {
+		if keepLast > 0 {
+			if _, ok := kept[digestKey{idx.ImageRef, idx.Digest.String()}]; ok {
+				continue
+			}
+		}
+
+		present, err := imageManifestPresent(ctx, client, idx.ImageRef)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check image %s: %w", idx.ImageRef, err)
+		}
+		if !present {
+			candidates = append(candidates, PruneCandidate{StoredIndex: idx, Reason: fmt.Sprintf("image %s no longer present", idx.ImageRef)})
+			continue
+		}
+
+		ok, err := blobsPresent(ctx, store, idx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check blobs for index %s: %w", idx.Digest, err)
+		}
+		if !ok {
+			candidates = append(candidates, PruneCandidate{StoredIndex: idx, Reason: "referenced blob(s) missing"})
+		}
+	}
+	return candidates, nil
+}
+
+// PruneStoredIndices removes each candidate's index manifest along with the
+// child blobs listed in its "manifests" field, and returns the total number of
+// bytes reclaimed across all of them. Blobs shared by more than one
+// candidate are only deleted, and counted, once.
+func PruneStoredIndices(ctx context.Context, store content.Store, candidates []PruneCandidate) (int64, error) {
+	var reclaimed int64
+	deleted := make(map[digest.Digest]struct{})
+	for _, c := range candidates {
+		children, err := readIndexChildren(ctx, store, c.Digest)
+		if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+			return reclaimed, fmt.Errorf("unable to read SOCI index %s: %w", c.Digest, err)
+		}
+
+		for _, d := range append([]digest.Digest{c.Digest}, children...) {
+			if _, ok := deleted[d]; ok {
+				continue
+			}
+			deleted[d] = struct{}{}
+
+			info, err := store.Info(ctx, d)
+			if err != nil {
+				if errors.Is(err, errdefs.ErrNotFound) {
+					continue
+				}
+				return reclaimed, fmt.Errorf("unable to stat blob %s: %w", d, err)
+			}
+			if err := store.Delete(ctx, d); err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+				return reclaimed, fmt.Errorf("unable to delete blob %s: %w", d, err)
+			}
+			reclaimed += info.Size
+		}
+	}
+	return reclaimed, nil
+}
+
+type digestKey struct {
+	imageRef string
+	digest   string
+}
+
+func imageManifestPresent(ctx context.Context, client *containerd.Client, imageRef string) (bool, error) {
+	_, err := client.ImageService().Get(ctx, imageRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// blobsPresent reports whether the index manifest and every blob listed in
+// its "manifests" field are still present in store.
+func blobsPresent(ctx context.Context, store content.Store, idx StoredIndex) (bool, error) {
+	if _, err := store.Info(ctx, idx.Digest); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	children, err := readIndexChildren(ctx, store, idx.Digest)
+	if err != nil {
+		return false, fmt.Errorf("unable to read SOCI index %s: %w", idx.Digest, err)
+	}
+
+	for _, child := range children {
+		if _, err := store.Info(ctx, child); err != nil {
+			if errdefs.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,162 @@
+/*
+   Copyright The Soci Snapshotter Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package soci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+// StoredIndex describes a single SOCI index found in local content storage,
+// together with the metadata needed to render and filter it.
+type StoredIndex struct {
+	Digest    digest.Digest
+	ImageRef  string
+	Platform  string
+	MediaType string
+	Size      int64
+	CreatedAt time.Time
+	Labels    map[string]string
+}
+
+// ListStoredIndices returns the metadata for every SOCI index present in the
+// containerd content store, in ascending digest order.
+//
+// Finding the candidate digests is a single filtered walk, but resolving
+// each one's metadata means actually opening and parsing that candidate's
+// blob, so once a host accumulates dozens of indices that per-index read
+// dominates. ListStoredIndices fans those reads out across a bounded pool
+// of goroutines. A candidate that turns out not to be a well-formed SOCI
+// index is skipped, the same as the prior serial walk did; a genuine read
+// error (content store failure, context cancellation) aborts the whole
+// listing via the errgroup.
+func ListStoredIndices(ctx context.Context, store content.Store) ([]StoredIndex, error) {
+	candidates, err := candidateIndices(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("unable to walk content store: %w", err)
+	}
+
+	var (
+		mu  sync.Mutex
+		out []StoredIndex
+	)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(runtime.NumCPU() * 2)
+	for _, candidate := range candidates {
+		candidate := candidate
+		g.Go(func() error {
+			idx, ok, err := readIndexMetadata(gCtx, store, candidate)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			mu.Lock()
+			out = append(out, idx)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Digest.String() < out[j].Digest.String() })
+	return out, nil
+}
+
+// candidateIndices does the single cheap walk over the content store,
+// filtering to blobs labeled as SOCI indices. It does no further I/O per
+// candidate; that work happens in readIndexMetadata.
+func candidateIndices(ctx context.Context, store content.Store) ([]content.Info, error) {
+	var candidates []content.Info
+	err := store.Walk(ctx, func(info content.Info) error {
+		candidates = append(candidates, info)
+		return nil
+	}, sociIndexLabelFilter)
+	return candidates, err
+}
+
+// readIndexMetadata resolves the manifest digest, size, and image ref for a
+// single SOCI index by opening and parsing its blob. It is safe to call
+// concurrently for different candidates against the same content.Store.
+//
+// The ok=false, err=nil return means info wasn't actually a SOCI index
+// (e.g. missing the labels `soci create` stamps on indices it writes) and
+// the caller should skip it rather than fail the whole listing; a non-nil
+// err means the blob itself could not be read and the caller should abort.
+func readIndexMetadata(ctx context.Context, store content.Store, info content.Info) (StoredIndex, bool, error) {
+	imageRef := info.Labels[imageRefLabel]
+	platform := info.Labels[platformLabel]
+	if imageRef == "" || platform == "" {
+		return StoredIndex{}, false, nil
+	}
+
+	ra, err := store.ReaderAt(ctx, ocispec.Descriptor{Digest: info.Digest, Size: info.Size})
+	if err != nil {
+		return StoredIndex{}, false, fmt.Errorf("unable to open SOCI index %s: %w", info.Digest, err)
+	}
+	defer ra.Close()
+
+	raw, err := io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+	if err != nil {
+		return StoredIndex{}, false, fmt.Errorf("unable to read SOCI index %s: %w", info.Digest, err)
+	}
+
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return StoredIndex{}, false, nil
+	}
+
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		mediaType = sociIndexMediaType
+	}
+
+	return StoredIndex{
+		Digest:    info.Digest,
+		ImageRef:  imageRef,
+		Platform:  platform,
+		MediaType: mediaType,
+		Size:      int64(len(raw)),
+		CreatedAt: info.CreatedAt,
+		Labels:    info.Labels,
+	}, true, nil
+}
+
+const (
+	// sociIndexLabelFilter selects content labeled as a SOCI index.
+	sociIndexLabelFilter = "labels.\"containerd.io/snapshot/soci/index\"==true"
+	imageRefLabel        = "containerd.io/snapshot/soci/image-ref"
+	platformLabel        = "containerd.io/snapshot/soci/platform"
+	sociIndexMediaType   = "application/vnd.amazon.soci.index.v1+json"
+)